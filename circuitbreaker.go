@@ -0,0 +1,137 @@
+package main
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// circuitState represents the current state of a circuitBreaker.
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+var circuitStateStrings = [...]string{
+	`closed`,
+	`open`,
+	`half-open`,
+}
+
+// String returns the definition of the circuitState.
+func (c circuitState) String() string {
+	return circuitStateStrings[c]
+}
+
+// circuitBreaker suspends collection against a single NetScaler instance
+// after repeated Nitro API failures and probes for recovery once the
+// cooldown period elapses.
+type circuitBreaker struct {
+	lock          sync.Mutex
+	state         circuitState
+	failures      int
+	maxFailures   int
+	cooldown      time.Duration
+	halfOpenAfter time.Time
+	trialInFlight bool
+}
+
+func newCircuitBreaker(maxFailures int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		maxFailures: maxFailures,
+		cooldown:    cooldown,
+	}
+}
+
+// allow reports whether a request should be attempted against the instance
+// the breaker guards, flipping an expired open breaker to half-open. Only
+// one caller is let through per half-open trial: once the breaker flips,
+// trialInFlight gates every other concurrent caller out until that single
+// trial's result is recorded, instead of letting the whole fan-out pile in
+// on a still-struggling instance the moment the cooldown expires.
+func (b *circuitBreaker) allow() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	switch b.state {
+	case circuitOpen:
+		if time.Now().Before(b.halfOpenAfter) || b.trialInFlight {
+			return false
+		}
+		b.state = circuitHalfOpen
+		b.trialInFlight = true
+		return true
+	case circuitHalfOpen:
+		return false
+	default:
+		return true
+	}
+}
+
+// recordSuccess closes the breaker, resets the failure count and releases
+// the half-open trial gate.
+func (b *circuitBreaker) recordSuccess() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.failures = 0
+	b.state = circuitClosed
+	b.trialInFlight = false
+}
+
+// recordFailure registers a failed attempt, tripping the breaker open once
+// maxFailures is reached. It returns the resulting state.
+func (b *circuitBreaker) recordFailure() circuitState {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.failures++
+	switch {
+	case b.state == circuitHalfOpen:
+		b.state = circuitOpen
+		b.halfOpenAfter = time.Now().Add(b.cooldown)
+		b.trialInFlight = false
+	case b.failures >= b.maxFailures:
+		b.state = circuitOpen
+		b.halfOpenAfter = time.Now().Add(b.cooldown)
+	}
+	return b.state
+}
+
+// currentState returns the breaker's current state.
+func (b *circuitBreaker) currentState() circuitState {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.state
+}
+
+// backoffWithJitter computes an exponential backoff duration for the given
+// attempt, capped at max and jittered by +/-10% to avoid thundering herds
+// across pools reconnecting at the same time.
+func backoffWithJitter(attempt int, base, max time.Duration) time.Duration {
+	d := base << uint(attempt)
+	if d <= 0 || d > max {
+		d = max
+	}
+	jitter := time.Duration(rand.Int63n(int64(d)/5 + 1))
+	d += jitter - d/10
+	if d > max {
+		d = max
+	}
+	return d
+}
+
+var netscalerUp = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: "netscaler",
+		Name:      "up",
+		Help:      "Whether the NetScaler instance is currently reachable by the exporter (1) or circuit-broken (0).",
+	},
+	[]string{"nsInstance"},
+)
+
+func init() {
+	prometheus.MustRegister(netscalerUp)
+}
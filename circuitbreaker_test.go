@@ -0,0 +1,101 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerClosedAllowsByDefault(t *testing.T) {
+	b := newCircuitBreaker(3, time.Minute)
+	if !b.allow() {
+		t.Fatalf("expected a new breaker to allow requests")
+	}
+	if got := b.currentState(); got != circuitClosed {
+		t.Fatalf("expected circuitClosed, got %s", got)
+	}
+}
+
+func TestCircuitBreakerOpensAfterMaxFailures(t *testing.T) {
+	b := newCircuitBreaker(3, time.Minute)
+	for i := 0; i < 2; i++ {
+		if state := b.recordFailure(); state != circuitClosed {
+			t.Fatalf("expected circuitClosed after %d failures, got %s", i+1, state)
+		}
+	}
+	if state := b.recordFailure(); state != circuitOpen {
+		t.Fatalf("expected circuitOpen after reaching maxFailures, got %s", state)
+	}
+	if b.allow() {
+		t.Fatalf("expected allow() to refuse requests while open and within cooldown")
+	}
+}
+
+func TestCircuitBreakerHalfOpenAllowsOnlyOneTrial(t *testing.T) {
+	b := newCircuitBreaker(1, time.Millisecond)
+	b.recordFailure()
+	if got := b.currentState(); got != circuitOpen {
+		t.Fatalf("expected circuitOpen, got %s", got)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	if !b.allow() {
+		t.Fatalf("expected the first caller past cooldown to be let through")
+	}
+	if got := b.currentState(); got != circuitHalfOpen {
+		t.Fatalf("expected circuitHalfOpen after the trial is claimed, got %s", got)
+	}
+	for i := 0; i < 5; i++ {
+		if b.allow() {
+			t.Fatalf("expected concurrent callers to be refused while a half-open trial is in flight")
+		}
+	}
+}
+
+func TestCircuitBreakerHalfOpenSuccessCloses(t *testing.T) {
+	b := newCircuitBreaker(1, time.Millisecond)
+	b.recordFailure()
+	time.Sleep(5 * time.Millisecond)
+	if !b.allow() {
+		t.Fatalf("expected trial caller to be let through")
+	}
+	b.recordSuccess()
+	if got := b.currentState(); got != circuitClosed {
+		t.Fatalf("expected circuitClosed after a successful trial, got %s", got)
+	}
+	if !b.allow() {
+		t.Fatalf("expected requests to be allowed again once closed")
+	}
+}
+
+func TestCircuitBreakerHalfOpenFailureReopens(t *testing.T) {
+	b := newCircuitBreaker(1, time.Millisecond)
+	b.recordFailure()
+	time.Sleep(5 * time.Millisecond)
+	if !b.allow() {
+		t.Fatalf("expected trial caller to be let through")
+	}
+	if state := b.recordFailure(); state != circuitOpen {
+		t.Fatalf("expected a failed trial to reopen the breaker, got %s", state)
+	}
+	if b.allow() {
+		t.Fatalf("expected allow() to refuse requests immediately after reopening")
+	}
+	time.Sleep(5 * time.Millisecond)
+	if !b.allow() {
+		t.Fatalf("expected a new trial to be allowed once the new cooldown elapses")
+	}
+}
+
+func TestBackoffWithJitterStaysWithinCap(t *testing.T) {
+	base := 250 * time.Millisecond
+	maxBackoff := 30 * time.Second
+	for attempt := 0; attempt < 20; attempt++ {
+		d := backoffWithJitter(attempt, base, maxBackoff)
+		if d <= 0 {
+			t.Fatalf("attempt %d: expected a positive backoff, got %s", attempt, d)
+		}
+		if d > maxBackoff {
+			t.Fatalf("attempt %d: backoff %s exceeded cap %s", attempt, d, maxBackoff)
+		}
+	}
+}
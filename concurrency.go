@@ -0,0 +1,191 @@
+package main
+
+import (
+	"container/ring"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	latencyWindowSize    = 256
+	concurrencyIncrement = 2
+	concurrencyBackoff   = 0.5
+	// errRateThreshold sits above the EWMA step a single isolated failure
+	// produces (errRateEWMAAlpha) so one transient blip doesn't trip the
+	// backoff; it takes a second failure in quick succession - a sustained
+	// rate, not a spike - to push errEWMA past it.
+	errRateThreshold = 0.25
+	errRateEWMAAlpha = 0.2
+	minConcurrency   = 1
+
+	// targetScrapeWindow is the budget a scrape is expected to complete
+	// within; it anchors the Little's-Law estimate of how many concurrent
+	// in-flight requests are needed to clear all servers inside it.
+	targetScrapeWindow = 30 * time.Second
+)
+
+// concurrencyController adaptively sizes a fan-out based on measured Nitro
+// latency and error rate, replacing a hardcoded fan-out constant. It tracks
+// a ring buffer of recent latencies for percentile reporting and an EWMA
+// error rate that drives an AIMD ceiling: additive increase while errors
+// stay below errRateThreshold, multiplicative decrease on a spike.
+type concurrencyController struct {
+	lock       sync.Mutex
+	latencies  *ring.Ring
+	errEWMA    float64
+	ceiling    int
+	maxWorkers int
+	nsInstance string
+	subsystem  string
+}
+
+func newConcurrencyController(nsInstance, subsystem string, maxWorkers int) *concurrencyController {
+	if maxWorkers < minConcurrency {
+		maxWorkers = minConcurrency
+	}
+	c := &concurrencyController{
+		latencies:  ring.New(latencyWindowSize),
+		ceiling:    maxWorkers,
+		maxWorkers: maxWorkers,
+		nsInstance: nsInstance,
+		subsystem:  subsystem,
+	}
+	concurrencyGauge.WithLabelValues(nsInstance, subsystem).Set(float64(c.ceiling))
+	return c
+}
+
+// observe records the latency and outcome of a single request and adjusts
+// the AIMD ceiling accordingly: a failing request that leaves the error
+// EWMA over errRateThreshold halves the ceiling, while the ceiling grows
+// additively as long as the EWMA stays at or below the threshold.
+func (c *concurrencyController) observe(latency time.Duration, failed bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.latencies.Value = latency
+	c.latencies = c.latencies.Next()
+	var errObs float64
+	if failed {
+		errObs = 1
+	}
+	c.errEWMA = errRateEWMAAlpha*errObs + (1-errRateEWMAAlpha)*c.errEWMA
+	switch {
+	case failed && c.errEWMA > errRateThreshold:
+		// Multiplicative decrease on each failing observation while the
+		// EWMA is over the threshold, not on every tick the EWMA stays
+		// elevated - otherwise the successful calls that follow a blip
+		// keep halving the ceiling while the EWMA decays back down.
+		next := int(math.Max(minConcurrency, math.Floor(float64(c.ceiling)*concurrencyBackoff)))
+		if next < c.ceiling {
+			concurrencyEvents.WithLabelValues(c.nsInstance, c.subsystem, "close").Inc()
+		}
+		c.ceiling = next
+	case c.errEWMA <= errRateThreshold && c.ceiling < c.maxWorkers:
+		c.ceiling += concurrencyIncrement
+		if c.ceiling >= c.maxWorkers {
+			c.ceiling = c.maxWorkers
+			// Only the step that fully recovers the ceiling counts as an
+			// "open" transition; the intermediate additive-increase steps
+			// getting there aren't transitions in their own right, while
+			// "close" fires on each halving step that lowers the ceiling.
+			concurrencyEvents.WithLabelValues(c.nsInstance, c.subsystem, "open").Inc()
+		}
+	}
+	concurrencyGauge.WithLabelValues(c.nsInstance, c.subsystem).Set(float64(c.ceiling))
+	avg, p95 := c.latencyStatsLocked()
+	latencySeconds.WithLabelValues(c.nsInstance, c.subsystem, "avg").Set(avg.Seconds())
+	latencySeconds.WithLabelValues(c.nsInstance, c.subsystem, "p95").Set(p95.Seconds())
+}
+
+// latencyStatsLocked computes the moving average and p95 over the current
+// window. Caller must hold c.lock.
+func (c *concurrencyController) latencyStatsLocked() (avg, p95 time.Duration) {
+	samples := make([]time.Duration, 0, latencyWindowSize)
+	c.latencies.Do(func(v interface{}) {
+		if d, ok := v.(time.Duration); ok {
+			samples = append(samples, d)
+		}
+	})
+	if len(samples) == 0 {
+		return 0, 0
+	}
+	var sum time.Duration
+	for _, d := range samples {
+		sum += d
+	}
+	avg = sum / time.Duration(len(samples))
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	idx := int(math.Ceil(0.95*float64(len(samples)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(samples) {
+		idx = len(samples) - 1
+	}
+	return avg, samples[idx]
+}
+
+// control returns the fan-out size to use for a batch of n servers: a
+// Little's-Law estimate of the concurrency needed to clear n servers within
+// targetScrapeWindow given the currently observed average latency, clamped
+// by the AIMD ceiling, the configured maxWorkers and n itself.
+func (c *concurrencyController) control(n int) int {
+	c.lock.Lock()
+	avg, _ := c.latencyStatsLocked()
+	ceiling := c.ceiling
+	c.lock.Unlock()
+	control := ceiling
+	if avg > 0 {
+		targetQPS := float64(n) / targetScrapeWindow.Seconds()
+		estimate := int(math.Ceil(targetQPS * avg.Seconds()))
+		if estimate < minConcurrency {
+			estimate = minConcurrency
+		}
+		if estimate < control {
+			control = estimate
+		}
+	}
+	if control > n {
+		control = n
+	}
+	if control < minConcurrency {
+		control = minConcurrency
+	}
+	return control
+}
+
+var (
+	concurrencyGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "netscaler",
+			Name:      "collector_worker_concurrency",
+			Help:      "Current adaptive fan-out concurrency used to collect stats for a subsystem.",
+		},
+		[]string{"nsInstance", "subSystem"},
+	)
+
+	latencySeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "netscaler",
+			Name:      "collector_request_latency_seconds",
+			Help:      "Observed Nitro request latency for a subsystem's adaptive fan-out, by quantile (avg, p95).",
+		},
+		[]string{"nsInstance", "subSystem", "quantile"},
+	)
+
+	concurrencyEvents = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "netscaler",
+			Name:      "collector_worker_concurrency_events_total",
+			Help:      "Count of adaptive concurrency open (increase) and close (backoff) transitions, by subsystem.",
+		},
+		[]string{"nsInstance", "subSystem", "event"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(concurrencyGauge, latencySeconds, concurrencyEvents)
+}
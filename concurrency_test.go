@@ -0,0 +1,96 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConcurrencyControllerIgnoresIsolatedFailure(t *testing.T) {
+	c := newConcurrencyController("test-instance", lbvserverSvcSubsystem, 10)
+	c.observe(10*time.Millisecond, true)
+	if c.ceiling != 10 {
+		t.Fatalf("expected a single isolated failure not to trip the breaker, ceiling changed to %d", c.ceiling)
+	}
+}
+
+func TestConcurrencyControllerBacksOffOnSustainedErrorRate(t *testing.T) {
+	c := newConcurrencyController("test-instance", lbvserverSvcSubsystem, 10)
+	c.observe(10*time.Millisecond, true)
+	c.observe(10*time.Millisecond, true)
+	if c.ceiling != 5 {
+		t.Fatalf("expected ceiling to halve to 5 once a second failure pushes errEWMA over threshold, got %d", c.ceiling)
+	}
+}
+
+func TestConcurrencyControllerBackoffNeverGoesBelowMinConcurrency(t *testing.T) {
+	c := newConcurrencyController("test-instance", lbvserverSvcSubsystem, 8)
+	for i := 0; i < 10; i++ {
+		c.observe(10*time.Millisecond, true)
+	}
+	if c.ceiling < minConcurrency {
+		t.Fatalf("expected ceiling to never drop below minConcurrency, got %d", c.ceiling)
+	}
+}
+
+func TestConcurrencyControllerRecoversCeilingAfterSustainedSuccess(t *testing.T) {
+	c := newConcurrencyController("test-instance", lbvserverSvcSubsystem, 10)
+	c.observe(10*time.Millisecond, true)
+	c.observe(10*time.Millisecond, true)
+	if c.ceiling != 5 {
+		t.Fatalf("expected ceiling to halve to 5, got %d", c.ceiling)
+	}
+	for i := 0; i < 50; i++ {
+		c.observe(10*time.Millisecond, false)
+	}
+	if c.ceiling != c.maxWorkers {
+		t.Fatalf("expected sustained success to climb the ceiling back to maxWorkers (%d), got %d", c.maxWorkers, c.ceiling)
+	}
+}
+
+func TestConcurrencyControllerControlClampsToN(t *testing.T) {
+	c := newConcurrencyController("test-instance", lbvserverSvcSubsystem, 10)
+	if got := c.control(3); got != 3 {
+		t.Fatalf("expected control(3) to clamp to n=3 when ceiling (10) exceeds it, got %d", got)
+	}
+	if got := c.control(100); got != 10 {
+		t.Fatalf("expected control(100) to clamp to the ceiling (10), got %d", got)
+	}
+}
+
+func TestConcurrencyControllerControlUsesLittlesLawEstimate(t *testing.T) {
+	c := newConcurrencyController("test-instance", lbvserverSvcSubsystem, 10)
+	for i := 0; i < latencyWindowSize; i++ {
+		c.observe(100*time.Millisecond, false)
+	}
+	// targetQPS = 300/30s = 10; estimate = ceil(10 * 0.1s) = 1, well under the
+	// ceiling, so control should follow the latency-derived estimate instead
+	// of the AIMD ceiling.
+	if got := c.control(300); got != 1 {
+		t.Fatalf("expected control(300) to follow the Little's-Law estimate of 1, got %d", got)
+	}
+}
+
+func TestConcurrencyControllerLatencyStatsAvgAndP95(t *testing.T) {
+	c := newConcurrencyController("test-instance", lbvserverSvcSubsystem, 10)
+	latencies := []time.Duration{
+		10 * time.Millisecond,
+		20 * time.Millisecond,
+		30 * time.Millisecond,
+		40 * time.Millisecond,
+		100 * time.Millisecond,
+	}
+	for _, d := range latencies {
+		c.observe(d, false)
+	}
+	c.lock.Lock()
+	avg, p95 := c.latencyStatsLocked()
+	c.lock.Unlock()
+	wantAvg := 40 * time.Millisecond
+	if avg != wantAvg {
+		t.Fatalf("expected avg latency %s, got %s", wantAvg, avg)
+	}
+	wantP95 := 100 * time.Millisecond
+	if p95 != wantP95 {
+		t.Fatalf("expected p95 latency %s, got %s", wantP95, p95)
+	}
+}
@@ -1,13 +1,11 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
-	"math"
-	"sync"
 	"time"
 
 	"github.com/jbvmio/netscaler"
-	"go.uber.org/zap"
 )
 
 // RawLBVServerStats is the payload as returned by the Nitro API.
@@ -71,89 +69,87 @@ func (s LBServiceStats) NitroType() string {
 	return lbvserverSvcSubsystem
 }
 
-func processLBVServerStats(P *Pool, wg *sync.WaitGroup) {
-	if wg != nil {
-		defer wg.Done()
+// lbvserverProbe implements Probe for the lbvserver subsystem, fanning out
+// to GetLBServerServiceStats. The concurrency guard, backoff-on-error and
+// submit-to-pipeline behavior it used to implement itself now live in the
+// shared probeMetricHandler wrapper.
+type lbvserverProbe struct {
+	pool *Pool
+}
+
+func newLBVServerProbe(p *Pool) Probe {
+	return &lbvserverProbe{pool: p}
+}
+
+// NitroStatsType implements Probe.
+func (pr *lbvserverProbe) NitroStatsType() netscaler.StatsType {
+	return netscaler.StatsTypeLBVServer
+}
+
+// Collect implements Probe.
+func (pr *lbvserverProbe) Collect(ctx context.Context) ([]NitroData, error) {
+	lbvServers, err := GetLBServerServiceStats(pr.pool, pr.NitroStatsType())
+	if err != nil {
+		return nil, err
 	}
-	thisSS := lbvserverSubsystem
-	switch {
-	case P.metricFlipBit[thisSS].good():
-		defer P.metricFlipBit[thisSS].flip()
-		switch {
-		case P.stopped:
-			P.logger.Info("Skipping sybSystem stat collection, process is stopping", zap.String("subSystem", thisSS))
-		default:
-			P.logger.Debug("Processing subSystem Stats", zap.String("subSystem", thisSS))
-			lbvServers, err := GetLBServerServiceStats(P)
-			switch {
-			case err != nil:
-				P.logger.Error("error retrieving data for subSystem stat collection", zap.String("subSystem", thisSS))
-				P.insertBackoff(thisSS)
-			default:
-				P.logger.Debug("processing lbservice stats", zap.String("subSystem", thisSS), zap.Int("number of lbvservers", len(lbvServers)))
-				for _, svr := range lbvServers {
-					req := newNitroDataReq(svr)
-					success := P.submit(req)
-					if !success {
-						exporterProcessingFailures.WithLabelValues(P.nsInstance, thisSS).Inc()
-					}
-				}
-				go TK.set(P.nsInstance, thisSS, float64(time.Now().UnixNano()))
-				P.logger.Debug("subSystem stat collection Complete", zap.String("subSystem", thisSS))
-			}
-		}
-	default:
-		P.logger.Info("subSystem stat collection already in progress", zap.String("subSystem", thisSS))
+	data := make([]NitroData, 0, len(lbvServers))
+	for _, svr := range lbvServers {
+		data = append(data, svr)
 	}
+	return data, nil
+}
+
+func init() {
+	RegisterSubsystem(lbvserverSubsystem, newLBVServerProbe)
 }
 
 // GetLBServerServiceStats retrieves stats for both GSLBServers and GSLBServices.
-func GetLBServerServiceStats(P *Pool) ([]LBVServerStats, error) {
+// The fan-out width is sized adaptively by P.lbvConcurrency from measured
+// Nitro latency and error rate rather than a fixed batch size: control is the
+// number of concurrent goroutines to run, so the server list is split into
+// exactly that many groups rather than used as a per-goroutine batch size.
+// Every Nitro call goes through P.nitroAPICall, so this subsystem shares the
+// same client-pool selection, retry/backoff and circuit breaker as
+// nitroAPITask instead of keeping its own independent copy of that logic.
+func GetLBServerServiceStats(P *Pool, statsType netscaler.StatsType) ([]LBVServerStats, error) {
 	var lbVServers []LBVServerStats
-	servers, err := getLBVServerStats(P.client)
+	if !P.breaker.allow() {
+		return lbVServers, errCircuitOpen
+	}
+	servers, err := getLBVServerStatsViaPool(P, statsType)
 	if err != nil {
 		exporterAPICollectFailures.WithLabelValues(P.nsInstance, lbvserverSubsystem).Inc()
 		return lbVServers, err
 	}
 	svcChan := make(chan []LBVServerStats, len(servers)+1)
 	errChan := make(chan bool, len(servers)+1)
-	var controlSize float64 = 40
-	control := int(math.Round((float64(len(servers)) / controlSize) + 0.6))
-	if control <= 1 {
-		control = len(servers)
+	control := P.lbvConcurrency.control(len(servers))
+	groups := make([][]LBVServerStats, control)
+	for i, svr := range servers {
+		groups[i%control] = append(groups[i%control], svr)
 	}
-	var count int
-	for count < len(servers) {
-		begin := count
-		end := count + control
-		if end > len(servers) {
-			end = len(servers)
+	for _, grp := range groups {
+		if len(grp) == 0 {
+			continue
 		}
-		svrGroups := servers[begin:end]
-		count = end
-		go func(groups []LBVServerStats) {
-			for _, grp := range groups {
-				var retries int
-				s, err := getLBVServerStats(P.client, grp.Name)
-			retryLoop:
-				for err != nil {
-					exporterAPICollectFailures.WithLabelValues(P.nsInstance, lbvserverSvcSubsystem).Inc()
-					if retries >= 3 {
-						break retryLoop
-					}
-					time.Sleep(time.Second * time.Duration(retries+1))
-					s, err = getLBVServerStats(P.client, grp.Name)
-					retries++
+		go func(group []LBVServerStats) {
+			for _, svr := range group {
+				if !P.breaker.allow() {
+					errChan <- false
+					continue
 				}
+				start := time.Now()
+				s, err := getLBVServerStatsViaPool(P, statsType, svr.Name)
+				P.lbvConcurrency.observe(time.Since(start), err != nil)
 				switch {
 				case err == nil:
 					svcChan <- s
 				default:
+					exporterAPICollectFailures.WithLabelValues(P.nsInstance, lbvserverSvcSubsystem).Inc()
 					errChan <- false
 				}
-
 			}
-		}(svrGroups)
+		}(grp)
 	}
 	for i := 0; i < len(servers); i++ {
 		select {
@@ -194,7 +190,36 @@ func GetLBServerServiceStatsOrig(P *Pool) ([]LBVServerStats, error) {
 	return lbVServers, nil
 }
 
-func getLBVServerStats(client *netscaler.NitroClient, target ...string) ([]LBVServerStats, error) {
+// getLBVServerStatsViaPool fetches and parses lbvserver stats exactly like
+// getLBVServerStats, but sources the client from P.getNextClient() and goes
+// through P.nitroAPICall for retry/backoff and circuit-breaker accounting
+// instead of taking a client directly, since this is the path real
+// collection runs through. statsType comes from the caller's
+// Probe.NitroStatsType() rather than being hardcoded here, so the registered
+// probe is the only place the subsystem's Nitro endpoint is named.
+func getLBVServerStatsViaPool(P *Pool, statsType netscaler.StatsType, target ...string) ([]LBVServerStats, error) {
+	var lbVServers []LBVServerStats
+	b, err := P.nitroAPICall(func(c NitroClient) ([]byte, error) {
+		switch len(target) {
+		case 0:
+			return c.GetAll(statsType)
+		default:
+			return c.Get(statsType, target[0]+`?statbindings=yes`)
+		}
+	})
+	if err != nil {
+		return lbVServers, err
+	}
+	tmp := struct {
+		Target *[]LBVServerStats `json:"lbvserver"`
+	}{Target: &lbVServers}
+	if err := json.Unmarshal(b, &tmp); err != nil {
+		return lbVServers, err
+	}
+	return lbVServers, nil
+}
+
+func getLBVServerStats(client NitroClient, target ...string) ([]LBVServerStats, error) {
 	var lbVServers []LBVServerStats
 	var b []byte
 	var err error
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"container/ring"
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/jbvmio/netscaler"
+	"go.uber.org/zap"
+)
+
+const lbvserverAllFixture = `{"lbvserver":[{"name":"vserver-1","state":"UP"},{"name":"vserver-2","state":"DOWN"}]}`
+
+func lbvserverTargetFixture(name string) string {
+	return `{"lbvserver":[{"name":"` + name + `","service":[{"name":"svc-` + name + `"}]}]}`
+}
+
+func TestGetLBVServerStats(t *testing.T) {
+	tests := []struct {
+		name      string
+		target    string
+		wantCount int
+		wantErr   bool
+	}{
+		{name: "GetAll", wantCount: 2},
+		{name: "targeted", target: "vserver-1", wantCount: 1},
+		{name: "missing target errors", target: "unknown", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeNitroClient().
+				withFixture(netscaler.StatsTypeLBVServer, []byte(lbvserverAllFixture)).
+				withTarget(netscaler.StatsTypeLBVServer, "vserver-1?statbindings=yes", []byte(lbvserverTargetFixture("vserver-1")))
+			var got []LBVServerStats
+			var err error
+			if tt.target == "" {
+				got, err = getLBVServerStats(client)
+			} else {
+				got, err = getLBVServerStats(client, tt.target)
+			}
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got) != tt.wantCount {
+				t.Fatalf("expected %d results, got %d", tt.wantCount, len(got))
+			}
+		})
+	}
+}
+
+// newTestPool builds a Pool wired the way newPoolWithClientFactory does for
+// the fields GetLBServerServiceStats depends on (client pool, breaker,
+// cancelable ctx), so tests exercise the same nitroAPICall path real
+// collection runs through rather than a hand-wired client field.
+func newTestPool(client NitroClient) *Pool {
+	idx := ring.New(1)
+	idx.Value = 0
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Pool{
+		clientPool:     []NitroClient{client},
+		poolIdx:        idx,
+		poolLock:       &sync.Mutex{},
+		nsInstance:     "test-instance",
+		logger:         zap.NewNop(),
+		lbvConcurrency: newConcurrencyController("test-instance", lbvserverSvcSubsystem, 4),
+		breaker:        newCircuitBreaker(breakerMaxFailures, breakerCooldown),
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+}
+
+func TestGetLBServerServiceStatsFanOut(t *testing.T) {
+	client := newFakeNitroClient().
+		withFixture(netscaler.StatsTypeLBVServer, []byte(lbvserverAllFixture)).
+		withTarget(netscaler.StatsTypeLBVServer, "vserver-1?statbindings=yes", []byte(lbvserverTargetFixture("vserver-1"))).
+		withTarget(netscaler.StatsTypeLBVServer, "vserver-2?statbindings=yes", []byte(lbvserverTargetFixture("vserver-2")))
+	p := newTestPool(client)
+
+	got, err := GetLBServerServiceStats(p, netscaler.StatsTypeLBVServer)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 lbvservers, got %d", len(got))
+	}
+}
+
+func TestGetLBServerServiceStatsRetriesOnError(t *testing.T) {
+	client := newFakeNitroClient().
+		withFixture(netscaler.StatsTypeLBVServer, []byte(lbvserverAllFixture)).
+		withTarget(netscaler.StatsTypeLBVServer, "vserver-1?statbindings=yes", []byte(lbvserverTargetFixture("vserver-1"))).
+		withTarget(netscaler.StatsTypeLBVServer, "vserver-2?statbindings=yes", []byte(lbvserverTargetFixture("vserver-2"))).
+		withFailures("vserver-1?statbindings=yes", 2)
+	p := newTestPool(client)
+
+	got, err := GetLBServerServiceStats(p, netscaler.StatsTypeLBVServer)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected retries to eventually recover both lbvservers, got %d", len(got))
+	}
+}
+
+func TestLBVServerProbeCollect(t *testing.T) {
+	client := newFakeNitroClient().
+		withFixture(netscaler.StatsTypeLBVServer, []byte(lbvserverAllFixture)).
+		withTarget(netscaler.StatsTypeLBVServer, "vserver-1?statbindings=yes", []byte(lbvserverTargetFixture("vserver-1"))).
+		withTarget(netscaler.StatsTypeLBVServer, "vserver-2?statbindings=yes", []byte(lbvserverTargetFixture("vserver-2")))
+	p := newTestPool(client)
+	probe := newLBVServerProbe(p)
+
+	data, err := probe.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(data) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(data))
+	}
+	for _, d := range data {
+		if d.NitroType() != lbvserverSubsystem {
+			t.Errorf("unexpected NitroType: %s", d.NitroType())
+		}
+	}
+}
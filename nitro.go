@@ -1,7 +1,12 @@
 package main
 
 import (
+	"context"
 	"sync"
+	"time"
+
+	"github.com/jbvmio/netscaler"
+	"go.uber.org/zap"
 )
 
 // TaskID defines the differents tasks available working with the Nitro API.
@@ -12,6 +17,7 @@ const (
 	nitroTaskRaw
 	nitroTaskData
 	nitroProm
+	nitroOTLP
 )
 
 var nitroTaskStrings = [...]string{
@@ -19,8 +25,15 @@ var nitroTaskStrings = [...]string{
 	`nitroTaskRaw`,
 	`nitroTaskData`,
 	`nitroProm`,
+	`nitroOTLP`,
 }
 
+// Sink names selectable via LBServer.Sinks.
+const (
+	sinkPrometheus = "prometheus"
+	sinkOTLP       = "otlp"
+)
+
 // ID returns the int ID.
 func (t TaskID) ID() int {
 	return int(t)
@@ -55,9 +68,88 @@ func defaultMetricHandleFunc(P *Pool, wg *sync.WaitGroup) {
 	wg.Done()
 }
 
-var metricsMap = map[string]metricHandleFunc{
-	servicesSubsystem: processSvcStats,
-	nsSubsystem:       processNSStats,
+// metricsMap holds any subsystem not registered via the Probe registry
+// below. Every built-in subsystem has been migrated onto RegisterSubsystem;
+// this stays in place for third-party handlers that still use the older
+// metricHandleFunc shape directly.
+var metricsMap = map[string]metricHandleFunc{}
+
+// Probe defines a self-contained Nitro statistics collector. A subsystem
+// implements Probe and registers a factory for it via RegisterSubsystem from
+// its own init(), rather than being wired into Pool construction directly.
+// NitroStatsType identifies which Nitro endpoint Collect fetches from, so the
+// fetch helper it delegates to stays the single place a subsystem's
+// netscaler.StatsType is named.
+//
+// Probe intentionally does not include a Describe([]*prometheus.Desc)
+// method. Every subsystem's metrics (concurrencyGauge, netscalerUp, the
+// per-NitroData gauges set in nitroPromTask, etc.) are package-level
+// prometheus.*Vec values registered once via prometheus.MustRegister in
+// their own files; there is no custom prometheus.Collector in this exporter
+// for a Probe-level Describe to feed. Add it back only if a subsystem needs
+// dynamically-shaped descriptors that a static Vec registration can't express.
+type Probe interface {
+	Collect(ctx context.Context) ([]NitroData, error)
+	NitroStatsType() netscaler.StatsType
+}
+
+// ProbeFactory builds a Probe bound to a specific Pool.
+type ProbeFactory func(*Pool) Probe
+
+// subsystemRegistry holds Probe factories keyed by subsystem name, as
+// registered by RegisterSubsystem.
+var subsystemRegistry = make(map[string]ProbeFactory)
+
+// RegisterSubsystem registers a probe factory under name so the subsystem
+// becomes selectable via LBServer.Metrics without modifying pool
+// construction code. Call this from a package init() in the subsystem's own
+// file, following the blank-import probe pattern.
+func RegisterSubsystem(name string, factory ProbeFactory) {
+	if _, exists := subsystemRegistry[name]; exists {
+		panic("netscaler-exporter: subsystem already registered: " + name)
+	}
+	subsystemRegistry[name] = factory
+}
+
+// probeMetricHandler adapts a registered Probe into the metricHandleFunc
+// shape consumed by Pool.collectMetrics: it applies the same per-subsystem
+// concurrency guard, backoff-on-error and submit-to-pipeline behavior that
+// every hand-written subsystem handler used to duplicate.
+func probeMetricHandler(subsystem string, probe Probe) metricHandleFunc {
+	return func(p *Pool, wg *sync.WaitGroup) {
+		if wg != nil {
+			defer wg.Done()
+		}
+		switch {
+		case p.metricFlipBit[subsystem].good():
+			defer p.metricFlipBit[subsystem].flip()
+			switch {
+			case p.stopped:
+				p.logger.Info("Skipping subSystem stat collection, process is stopping", zap.String("subSystem", subsystem))
+			default:
+				p.logger.Debug("Processing subSystem Stats", zap.String("subSystem", subsystem))
+				data, err := probe.Collect(p.ctx)
+				switch {
+				case err != nil:
+					p.logger.Error("error retrieving data for subSystem stat collection", zap.String("subSystem", subsystem))
+					p.insertBackoff(subsystem)
+				default:
+					p.logger.Debug("processing subSystem stats", zap.String("subSystem", subsystem), zap.Int("number of results", len(data)))
+					for _, d := range data {
+						req := p.newNitroDataReq(d)
+						success := p.submit(req)
+						if !success {
+							exporterProcessingFailures.WithLabelValues(p.nsInstance, subsystem).Inc()
+						}
+					}
+					go TK.set(p.nsInstance, subsystem, float64(time.Now().UnixNano()))
+					p.logger.Debug("subSystem stat collection Complete", zap.String("subSystem", subsystem))
+				}
+			}
+		default:
+			p.logger.Info("subSystem stat collection already in progress", zap.String("subSystem", subsystem))
+		}
+	}
 }
 
 func (p *Pool) collectMetrics(wg *sync.WaitGroup) {
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"time"
+
+	"github.com/jbvmio/netscaler"
+)
+
+// NitroClient is the subset of *netscaler.NitroClient's behavior Pool
+// depends on. Defining it as an interface lets tests substitute an
+// in-memory fake instead of dialing a real NetScaler appliance.
+type NitroClient interface {
+	GetAll(netscaler.StatsType) ([]byte, error)
+	Get(netscaler.StatsType, string) ([]byte, error)
+	Connect() error
+	Disconnect() error
+	WithHTTPTimeout(time.Duration)
+}
+
+// NitroClientFactory builds a NitroClient for the given LBServer. Pass a
+// custom factory to newPoolWithClientFactory to point a Pool at a fake
+// client in tests instead of a real NetScaler appliance.
+type NitroClientFactory func(LBServer) (NitroClient, error)
+
+// defaultNitroClientFactory builds a real *netscaler.NitroClient, matching
+// the construction newPool has always performed.
+func defaultNitroClientFactory(lbs LBServer) (NitroClient, error) {
+	return netscaler.NewNitroClient(lbs.URL, lbs.User, lbs.Pass, lbs.IgnoreCert)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jbvmio/netscaler"
+)
+
+// fakeNitroClient is an in-memory NitroClient that serves canned JSON
+// fixtures, letting Pool be exercised in tests without a live NetScaler
+// appliance.
+type fakeNitroClient struct {
+	lock         sync.Mutex
+	fixtures     map[netscaler.StatsType][]byte
+	targeted     map[netscaler.StatsType]map[string][]byte
+	failUntil    map[string]int
+	getAllCalls  map[netscaler.StatsType]int
+	getCalls     map[string]int
+	connectCalls int
+	connectErr   error
+}
+
+func newFakeNitroClient() *fakeNitroClient {
+	return &fakeNitroClient{
+		fixtures:    make(map[netscaler.StatsType][]byte),
+		targeted:    make(map[netscaler.StatsType]map[string][]byte),
+		failUntil:   make(map[string]int),
+		getAllCalls: make(map[netscaler.StatsType]int),
+		getCalls:    make(map[string]int),
+	}
+}
+
+func (f *fakeNitroClient) withFixture(statType netscaler.StatsType, body []byte) *fakeNitroClient {
+	f.fixtures[statType] = body
+	return f
+}
+
+func (f *fakeNitroClient) withTarget(statType netscaler.StatsType, target string, body []byte) *fakeNitroClient {
+	if f.targeted[statType] == nil {
+		f.targeted[statType] = make(map[string][]byte)
+	}
+	f.targeted[statType][target] = body
+	return f
+}
+
+// withFailures makes the next n Get calls for target fail before the fixture
+// is served, exercising retry-on-error paths.
+func (f *fakeNitroClient) withFailures(target string, n int) *fakeNitroClient {
+	f.failUntil[target] = n
+	return f
+}
+
+// GetAll implements NitroClient.
+func (f *fakeNitroClient) GetAll(statType netscaler.StatsType) ([]byte, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.getAllCalls[statType]++
+	body, ok := f.fixtures[statType]
+	if !ok {
+		return nil, fmt.Errorf("fakeNitroClient: no GetAll fixture for %v", statType)
+	}
+	return body, nil
+}
+
+// Get implements NitroClient.
+func (f *fakeNitroClient) Get(statType netscaler.StatsType, target string) ([]byte, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.getCalls[target]++
+	if remaining := f.failUntil[target]; remaining > 0 {
+		f.failUntil[target] = remaining - 1
+		return nil, fmt.Errorf("fakeNitroClient: simulated failure for %s", target)
+	}
+	targets, ok := f.targeted[statType]
+	if !ok {
+		return nil, fmt.Errorf("fakeNitroClient: no targeted fixtures for %v", statType)
+	}
+	body, ok := targets[target]
+	if !ok {
+		return nil, fmt.Errorf("fakeNitroClient: no fixture for target %s", target)
+	}
+	return body, nil
+}
+
+// Connect implements NitroClient.
+func (f *fakeNitroClient) Connect() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.connectCalls++
+	return f.connectErr
+}
+
+// Disconnect implements NitroClient.
+func (f *fakeNitroClient) Disconnect() error { return nil }
+
+// WithHTTPTimeout implements NitroClient.
+func (f *fakeNitroClient) WithHTTPTimeout(time.Duration) {}
+
+var _ NitroClient = (*fakeNitroClient)(nil)
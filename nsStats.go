@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jbvmio/netscaler"
+)
+
+// RawNSStats is the payload as returned by the Nitro API.
+type RawNSStats []byte
+
+// Len returns the size of the underlying []byte.
+func (r RawNSStats) Len() int {
+	return len(r)
+}
+
+// NSStats represents the data returned from the /stat/ns Nitro API endpoint.
+type NSStats struct {
+	CPUUsagePcnt      string `json:"cpuusagepcnt"`
+	MemUsagePcnt      string `json:"memusagepcnt"`
+	HTTPRequests      string `json:"httprequests"`
+	HTTPResponses     string `json:"httpresponses"`
+	TCPCurClientConn  string `json:"tcpcurclientconn"`
+	TCPCurServerConn  string `json:"tcpcurserverconn"`
+	RequestBytesRate  string `json:"requestbytesrate"`
+	ResponseBytesRate string `json:"responsebytesrate"`
+}
+
+// NitroType implements the NitroData interface.
+func (s NSStats) NitroType() string {
+	return nsSubsystem
+}
+
+// nsProbe implements Probe for the ns subsystem, migrating processNSStats
+// off the old metricsMap dispatch and onto the Probe registry so it
+// self-registers via init() instead of being wired into Pool by name.
+type nsProbe struct {
+	pool *Pool
+}
+
+func newNSProbe(p *Pool) Probe {
+	return &nsProbe{pool: p}
+}
+
+// NitroStatsType implements Probe.
+func (pr *nsProbe) NitroStatsType() netscaler.StatsType {
+	return netscaler.StatsTypeNS
+}
+
+// Collect implements Probe.
+func (pr *nsProbe) Collect(ctx context.Context) ([]NitroData, error) {
+	stats, err := getNSStats(pr.pool, pr.NitroStatsType())
+	if err != nil {
+		return nil, err
+	}
+	return []NitroData{stats}, nil
+}
+
+func init() {
+	RegisterSubsystem(nsSubsystem, newNSProbe)
+}
+
+// getNSStats fetches and parses ns stats through P.nitroAPICall, the same
+// client-pool selection, retry/backoff and circuit breaker
+// getLBVServerStatsViaPool uses for the lbvserver subsystem. statsType comes
+// from the caller's Probe.NitroStatsType() rather than being hardcoded here.
+func getNSStats(P *Pool, statsType netscaler.StatsType) (NSStats, error) {
+	var stats NSStats
+	if !P.breaker.allow() {
+		return stats, errCircuitOpen
+	}
+	b, err := P.nitroAPICall(func(c NitroClient) ([]byte, error) {
+		return c.GetAll(statsType)
+	})
+	if err != nil {
+		return stats, err
+	}
+	tmp := struct {
+		Target *NSStats `json:"ns"`
+	}{Target: &stats}
+	if err := json.Unmarshal(b, &tmp); err != nil {
+		return stats, err
+	}
+	return stats, nil
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jbvmio/work"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.uber.org/zap"
+)
+
+// otlpExporter owns the OTLP metrics pipeline for a single Pool: one meter
+// provider pushing to a configurable collector endpoint over gRPC.
+// Instruments are registered lazily the first time a given NitroData field
+// is seen, so adding a subsystem never requires wiring anything into this
+// file.
+type otlpExporter struct {
+	provider *sdkmetric.MeterProvider
+	meter    metric.Meter
+
+	lock     sync.Mutex
+	values   map[string]float64
+	gauges   map[string]struct{}
+	counters map[string]struct{}
+}
+
+// newOTLPExporter dials endpoint and builds a meter provider whose resource
+// identifies the NetScaler instance the metrics came from.
+func newOTLPExporter(ctx context.Context, endpoint, nsInstance, lbserverURL, haRole string) (*otlpExporter, error) {
+	exp, err := otlpmetricgrpc.New(ctx,
+		otlpmetricgrpc.WithEndpoint(endpoint),
+		otlpmetricgrpc.WithInsecure(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.New(ctx,
+		resource.WithAttributes(
+			semconv.ServiceNameKey.String("netscaler-exporter"),
+			attribute.String("nsInstance", nsInstance),
+			attribute.String("lbserverUrl", lbserverURL),
+			attribute.String("haRole", haRole),
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+	provider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithResource(res),
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exp)),
+	)
+	return &otlpExporter{
+		provider: provider,
+		meter:    provider.Meter("netscaler-exporter"),
+		values:   make(map[string]float64),
+		gauges:   make(map[string]struct{}),
+		counters: make(map[string]struct{}),
+	}, nil
+}
+
+// shutdown flushes and closes the underlying OTLP pipeline.
+func (o *otlpExporter) shutdown(ctx context.Context) error {
+	return o.provider.Shutdown(ctx)
+}
+
+// record converts data's numeric fields into OTLP points, keyed by NitroType
+// plus the field's json tag, and registers an instrument for any point not
+// already being reported. Fields whose json tag starts with "tot" follow the
+// Nitro API's "tot(al)*" naming for its cumulative counters (totalrequests,
+// tothits, and so on) and are reported as OTLP counters; everything else
+// (the "cur*" point-in-time fields, rates, percentages) is reported as a
+// gauge.
+func (o *otlpExporter) record(data NitroData) {
+	nitroType := data.NitroType()
+	v := reflect.ValueOf(data)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("json")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		var val float64
+		switch fv := v.Field(i).Interface().(type) {
+		case CurState:
+			val = fv.Value()
+		case string:
+			f, err := strconv.ParseFloat(fv, 64)
+			if err != nil {
+				continue
+			}
+			val = f
+		default:
+			continue
+		}
+		o.observe(nitroType+"_"+tag, val, strings.HasPrefix(tag, "tot"))
+	}
+}
+
+func (o *otlpExporter) observe(name string, val float64, counter bool) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.values[name] = val
+	if counter {
+		o.registerCounterLocked(name)
+		return
+	}
+	o.registerGaugeLocked(name)
+}
+
+// registerCounterLocked registers name as an OTLP observable counter the
+// first time it is seen. Nitro's "tot*" fields are already cumulative
+// since process start, so the last-observed value is reported as-is rather
+// than derived as a delta. o.lock must be held by the caller.
+func (o *otlpExporter) registerCounterLocked(name string) {
+	if _, registered := o.counters[name]; registered {
+		return
+	}
+	o.counters[name] = struct{}{}
+	counterName := name
+	_, _ = o.meter.Float64ObservableCounter(counterName,
+		metric.WithFloat64Callback(func(_ context.Context, result metric.Float64Observer) error {
+			o.lock.Lock()
+			defer o.lock.Unlock()
+			result.Observe(o.values[counterName])
+			return nil
+		}),
+	)
+}
+
+// registerGaugeLocked registers name as an OTLP observable gauge the first
+// time it is seen. o.lock must be held by the caller.
+func (o *otlpExporter) registerGaugeLocked(name string) {
+	if _, registered := o.gauges[name]; registered {
+		return
+	}
+	o.gauges[name] = struct{}{}
+	gaugeName := name
+	_, _ = o.meter.Float64ObservableGauge(gaugeName,
+		metric.WithFloat64Callback(func(_ context.Context, result metric.Float64Observer) error {
+			o.lock.Lock()
+			defer o.lock.Unlock()
+			result.Observe(o.values[gaugeName])
+			return nil
+		}),
+	)
+}
+
+// nitroOTLPTask hands a NitroData payload off to the pool's OTLP exporter.
+// It mirrors nitroPromTask's shape so the two sinks can be wired
+// interchangeably from dispatchToSinks.
+func (p *Pool) nitroOTLPTask(req work.TaskRequest) {
+	defer p.poolWG.Done()
+	timeNow := time.Now().UnixNano()
+	p.logger.Debug("Recieved nitroOTLP Task", zap.String("TaskType", req.ReqType().String()), zap.Int64("TaskTS", timeNow))
+	R := req.(*nitroTaskReq)
+	if data, ok := R.data.(NitroData); ok && p.otlp != nil {
+		p.otlp.record(data)
+	}
+	if R.ResultChan() != nil {
+		R.ResultChan() <- true
+		close(R.ResultChan())
+	}
+	p.logger.Debug("Completed nitroOTLP Task", zap.String("TaskType", req.ReqType().String()), zap.Int64("TaskTS", timeNow))
+}
+
+func (p *Pool) newOTLPReq(n NitroData) *nitroTaskReq {
+	return &nitroTaskReq{
+		taskID: nitroOTLP,
+		data:   n,
+		result: work.NewResultChannel(),
+		ctx:    p.ctx,
+	}
+}
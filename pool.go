@@ -2,8 +2,9 @@ package main
 
 import (
 	"container/ring"
+	"context"
 	"encoding/json"
-	"log"
+	"errors"
 	"sync"
 	"time"
 
@@ -12,25 +13,54 @@ import (
 	"go.uber.org/zap"
 )
 
+// errCircuitOpen is returned (via ResultChan) when a Nitro API request is
+// skipped because the instance's circuit breaker is currently open.
+var errCircuitOpen = errors.New("circuit breaker open for nsInstance")
+
 // Pool for exporting metrics for a lbserver.
 type Pool struct {
 	team           *work.Team
-	client         *netscaler.NitroClient
-	clientPool     []*netscaler.NitroClient
+	client         NitroClient
+	clientPool     []NitroClient
 	poolIdx        *ring.Ring
 	poolLock       *sync.Mutex
 	poolWG         sync.WaitGroup
+	submitLock     sync.Mutex
 	metricHandlers map[string]metricHandleFunc
+	metricFlipBit  map[string]collectBit
 	flipBit        collectBit
 	lbserver       LBServer
 	nsInstance     string
+	haRole         string
 	vipMap         VIPMap
 	mappingsLoaded bool
 	stopped        bool
+	breaker        *circuitBreaker
+	lbvConcurrency *concurrencyController
+	otlp           *otlpExporter
+	ctx            context.Context
+	cancel         context.CancelFunc
+	shutdownOnce   sync.Once
 	logger         *zap.Logger
 }
 
+const (
+	maxNitroRetries    = 5
+	nitroRetryBase     = 250 * time.Millisecond
+	nitroRetryCap      = 30 * time.Second
+	breakerMaxFailures = 3
+	breakerCooldown    = time.Minute
+)
+
 func newPool(lbs LBServer, metricsChan chan bool, logger *zap.Logger) *Pool {
+	return newPoolWithClientFactory(lbs, metricsChan, logger, defaultNitroClientFactory)
+}
+
+// newPoolWithClientFactory builds a Pool exactly as newPool does, but
+// sources its NitroClients from factory instead of always dialing a real
+// NetScaler appliance. Tests use this with a factory that returns a
+// fakeNitroClient to exercise Pool without a live instance.
+func newPoolWithClientFactory(lbs LBServer, metricsChan chan bool, logger *zap.Logger, factory NitroClientFactory) *Pool {
 	conf := work.NewTeamConfig()
 	conf.Name = lbs.URL
 	conf.Workers = lbs.PoolWorkers
@@ -45,6 +75,7 @@ func newPool(lbs LBServer, metricsChan chan bool, logger *zap.Logger) *Pool {
 		nsInstance: nsInstance(lbs.URL),
 		logger:     logger.With(zap.String(`nsInstance`, nsInstance(lbs.URL))),
 	}
+	pool.ctx, pool.cancel = context.WithCancel(context.Background())
 	team.Logger = pool.logger
 	pool.logger.Info("registered netscaler instance")
 	pool.logger.Info("registered lbserverUrl", zap.String("lbserverUrl", lbs.URL))
@@ -52,22 +83,31 @@ func newPool(lbs LBServer, metricsChan chan bool, logger *zap.Logger) *Pool {
 		mappings: make(map[string]map[string]string),
 		lock:     sync.Mutex{},
 	}
+	pool.haRole = lbs.HARole
 	pool.logger.Info("registering metrics")
 	metricHandlers := make(map[string]metricHandleFunc, len(lbs.Metrics))
+	metricFlipBit := make(map[string]collectBit, len(lbs.Metrics))
 	for _, m := range lbs.Metrics {
-		_, ok := metricsMap[m]
-		switch {
+		switch factory, ok := subsystemRegistry[m]; {
 		case ok:
-			pool.logger.Info("registering metric", zap.String("metric", m))
-			metricHandlers[m] = metricsMap[m]
+			pool.logger.Info("registering probe-backed metric", zap.String("metric", m))
+			metricHandlers[m] = probeMetricHandler(m, factory(&pool))
+			metricFlipBit[m] = collectBit{}
 		default:
-			pool.logger.Warn("invalid metric", zap.String("metric", m))
+			if handler, ok := metricsMap[m]; ok {
+				pool.logger.Info("registering metric", zap.String("metric", m))
+				metricHandlers[m] = handler
+				metricFlipBit[m] = collectBit{}
+			} else {
+				pool.logger.Warn("invalid metric", zap.String("metric", m))
+			}
 		}
 	}
 	pool.metricHandlers = metricHandlers
-	clientPool := make([]*netscaler.NitroClient, team.Config.Workers)
+	pool.metricFlipBit = metricFlipBit
+	clientPool := make([]NitroClient, team.Config.Workers)
 	for i := 0; i < team.Config.Workers; i++ {
-		client, err := netscaler.NewNitroClient(lbs.URL, lbs.User, lbs.Pass, lbs.IgnoreCert)
+		client, err := factory(lbs)
 		if err != nil {
 			pool.logger.Fatal("error creating additional client", zap.Error(err))
 		}
@@ -81,36 +121,97 @@ func newPool(lbs LBServer, metricsChan chan bool, logger *zap.Logger) *Pool {
 		pool.poolIdx = pool.poolIdx.Next()
 	}
 	pool.clientPool = clientPool
+	pool.breaker = newCircuitBreaker(breakerMaxFailures, breakerCooldown)
+	pool.lbvConcurrency = newConcurrencyController(pool.nsInstance, lbvserverSvcSubsystem, lbs.PoolWorkers)
+	netscalerUp.WithLabelValues(pool.nsInstance).Set(1)
 	pool.team.AddTask(int(nitroTaskAPI), pool.nitroAPITask)
 	pool.team.AddTask(int(nitroTaskRaw), pool.nitroRawTask)
 	pool.team.AddTask(int(nitroTaskData), pool.nitroDataTask)
 	pool.team.AddTask(int(nitroProm), pool.nitroPromTask)
+	for _, sink := range lbs.Sinks {
+		if sink != sinkOTLP {
+			continue
+		}
+		otlp, err := newOTLPExporter(pool.ctx, lbs.OTLPEndpoint, pool.nsInstance, lbs.URL, pool.haRole)
+		if err != nil {
+			pool.logger.Error("error creating OTLP exporter, otlp sink disabled", zap.Error(err))
+			break
+		}
+		pool.otlp = otlp
+		pool.team.AddTask(int(nitroOTLP), pool.nitroOTLPTask)
+		break
+	}
 	return &pool
 }
 
 func (p *Pool) submit(request work.TaskRequest) bool {
-	switch {
-	case p.stopped:
+	p.submitLock.Lock()
+	if p.stopped || p.ctx.Err() != nil {
+		p.submitLock.Unlock()
 		if request.ResultChan() != nil {
 			request.ResultChan() <- false
 			close(request.ResultChan())
 		}
 		return false
-	default:
-		return p.team.Submit(request)
 	}
+	// Add happens while still holding submitLock, so Shutdown can't
+	// observe poolWG at zero and start Wait() between our stopped check
+	// and our Add - it must wait for this lock first.
+	p.poolWG.Add(1)
+	p.submitLock.Unlock()
+	submitted := p.team.Submit(request)
+	if !submitted {
+		p.poolWG.Done()
+	}
+	return submitted
+}
+
+// Shutdown cancels the pool's root context so in-flight Nitro calls and
+// queued submits abort promptly, waits for outstanding work tracked by
+// poolWG to drain (bounded by ctx's deadline), and disconnects every client
+// in the pool exactly once. It replaces the previous closeClientPool +
+// log.Fatalf teardown with orderly shutdown that a caller can bound.
+func (p *Pool) Shutdown(ctx context.Context) error {
+	p.submitLock.Lock()
+	p.stopped = true
+	p.submitLock.Unlock()
+	p.cancel()
+	drained := make(chan struct{})
+	go func() {
+		p.poolWG.Wait()
+		close(drained)
+	}()
+	var err error
+	select {
+	case <-drained:
+		p.logger.Info("pool drained cleanly")
+	case <-ctx.Done():
+		err = ctx.Err()
+		p.logger.Warn("shutdown deadline exceeded with tasks still in flight", zap.Error(err))
+	}
+	p.shutdownOnce.Do(p.closeClientPool)
+	return err
 }
 
 func (p *Pool) closeClientPool() {
 	for _, client := range p.clientPool {
 		client.Disconnect()
 	}
-	p.client.Disconnect()
+	if p.client != nil {
+		p.client.Disconnect()
+	}
+	if p.otlp != nil {
+		if err := p.otlp.shutdown(context.Background()); err != nil {
+			p.logger.Warn("error shutting down OTLP exporter", zap.Error(err))
+		}
+	}
 }
 
-func (p *Pool) getNextClient() *netscaler.NitroClient {
+func (p *Pool) getNextClient() NitroClient {
+	p.poolLock.Lock()
 	i := p.poolIdx.Value.(int)
 	p.poolIdx = p.poolIdx.Next()
+	p.poolLock.Unlock()
 	p.logger.Debug("Retrieving Next Client in Client Pool", zap.Int("Client ID", i))
 	return p.clientPool[i]
 }
@@ -132,36 +233,54 @@ func (t nitroTaskReq) ConsistID() string {
 }
 
 func (p *Pool) nitroAPITask(req work.TaskRequest) {
+	defer p.poolWG.Done()
 	timeNow := time.Now().UnixNano()
 	p.logger.Debug("Recieved nitroAPI Task", zap.String("TaskType", req.ReqType().String()), zap.Int64("TaskTS", timeNow))
 	var b []byte
 	var err error
-	client := p.getNextClient()
 	R := req.(*nitroTaskReq)
+	if !p.breaker.allow() {
+		p.logger.Warn("circuit breaker open, skipping Nitro API call", zap.String("TaskType", req.ReqType().String()))
+		R.ResultChan() <- errCircuitOpen
+		close(R.ResultChan())
+		return
+	}
 	switch len(R.targets) {
 	case 0:
 		p.logger.Debug("Sending GetAll API Req", zap.String("TaskType", req.ReqType().String()), zap.Int64("TaskTS", timeNow))
-		b, err = client.GetAll(R.nitroID)
+		b, err = p.nitroAPICall(func(c NitroClient) ([]byte, error) {
+			return c.GetAll(R.nitroID)
+		})
 		if err != nil {
-			p.closeClientPool()
-			log.Fatalf("error retrieving data: %v\n", err)
+			p.logger.Error("error retrieving data", zap.String("TaskType", req.ReqType().String()), zap.Error(err))
+			R.ResultChan() <- err
+			close(R.ResultChan())
+			return
 		}
 	case 1:
 		p.logger.Debug("Sending Targed API Req", zap.String("TaskType", req.ReqType().String()), zap.Int64("TaskTS", timeNow))
 		t := R.targets[0]
-		b, err = client.Get(R.nitroID, t)
+		b, err = p.nitroAPICall(func(c NitroClient) ([]byte, error) {
+			return c.Get(R.nitroID, t)
+		})
 		if err != nil {
-			p.closeClientPool()
-			log.Fatalf("error retrieving data: %v\n", err)
+			p.logger.Error("error retrieving data", zap.String("TaskType", req.ReqType().String()), zap.Error(err))
+			R.ResultChan() <- err
+			close(R.ResultChan())
+			return
 		}
 	default:
 		p.logger.Debug("Sending MultiTargeted API Req - SHOULD NOT SEE!!", zap.String("TaskType", req.ReqType().String()), zap.Int64("TaskTS", timeNow))
 		for _, t := range R.targets {
-			apiReq := newNitroAPIReq(netscaler.StatsType(R.nitroID), t)
+			apiReq := p.newNitroAPIReq(netscaler.StatsType(R.nitroID), t)
 			p.submit(apiReq)
 			data := <-apiReq.ResultChan()
-			b := data.([]byte)
-			rawReq := newNitroRawReq(RawData(b))
+			b, ok := data.([]byte)
+			if !ok {
+				p.logger.Error("skipping target in multi-targeted API req, nested request failed", zap.String("TaskType", req.ReqType().String()))
+				continue
+			}
+			rawReq := p.newNitroRawReq(RawData(b))
 			p.submit(rawReq)
 			<-rawReq.ResultChan()
 		}
@@ -174,10 +293,57 @@ func (p *Pool) nitroAPITask(req work.TaskRequest) {
 	p.logger.Debug("Completed nitroAPI Task", zap.String("TaskType", req.ReqType().String()), zap.Int64("TaskTS", timeNow))
 }
 
+// nitroAPICall executes call against the next client in the pool, retrying
+// with backoff and re-connecting the client on transient failures. A
+// persistent failure trips the per-instance circuit breaker and suspends
+// further collection until the cooldown elapses.
+func (p *Pool) nitroAPICall(call func(NitroClient) ([]byte, error)) ([]byte, error) {
+	client := p.getNextClient()
+	var b []byte
+	var err error
+	for attempt := 0; attempt <= maxNitroRetries; attempt++ {
+		if p.ctx.Err() != nil {
+			return nil, p.ctx.Err()
+		}
+		b, err = call(client)
+		if err == nil {
+			p.breaker.recordSuccess()
+			netscalerUp.WithLabelValues(p.nsInstance).Set(1)
+			return b, nil
+		}
+		exporterAPICollectFailures.WithLabelValues(p.nsInstance, nitroTaskAPI.String()).Inc()
+		state := p.breaker.recordFailure()
+		if state == circuitOpen {
+			netscalerUp.WithLabelValues(p.nsInstance).Set(0)
+		}
+		if attempt == maxNitroRetries {
+			break
+		}
+		p.logger.Warn("Nitro API call failed, retrying", zap.Int("attempt", attempt+1), zap.Error(err))
+		select {
+		case <-time.After(backoffWithJitter(attempt, nitroRetryBase, nitroRetryCap)):
+		case <-p.ctx.Done():
+			return nil, p.ctx.Err()
+		}
+		if reconnErr := client.Connect(); reconnErr != nil {
+			p.logger.Warn("error reconnecting Nitro client", zap.Error(reconnErr))
+		}
+	}
+	return nil, err
+}
+
 func (p *Pool) nitroRawTask(req work.TaskRequest) {
+	defer p.poolWG.Done()
 	timeNow := time.Now().UnixNano()
 	p.logger.Debug("Recieved nitroRaw Task", zap.String("TaskType", req.ReqType().String()), zap.Int64("TaskTS", timeNow))
 	R := req.(*nitroTaskReq)
+	if R.ctx.Err() != nil {
+		p.logger.Debug("skipping nitroRaw Task, context cancelled", zap.String("TaskType", req.ReqType().String()), zap.Int64("TaskTS", timeNow))
+		if R.ResultChan() != nil {
+			close(R.ResultChan())
+		}
+		return
+	}
 	switch data := R.data.(type) {
 	case RawServiceStats:
 		p.logger.Debug("Identified nitroRaw Task Type as RawServiceStats", zap.String("TaskType", req.ReqType().String()), zap.Int64("TaskTS", timeNow))
@@ -194,7 +360,7 @@ func (p *Pool) nitroRawTask(req work.TaskRequest) {
 		}
 		p.logger.Debug("Processed RawServiceStats", zap.String("TaskType", req.ReqType().String()), zap.Int("Number of Stats", len(stats)), zap.Int64("TaskTS", timeNow))
 		for _, s := range stats {
-			datReq := newNitroDataReq(s)
+			datReq := p.newNitroDataReq(s)
 			success := p.submit(datReq)
 			p.logger.Debug("Sending nitroData Task", zap.String("TaskType", req.ReqType().String()), zap.Int64("TaskTS", timeNow), zap.Bool("successful", success))
 		}
@@ -205,17 +371,23 @@ func (p *Pool) nitroRawTask(req work.TaskRequest) {
 }
 
 func (p *Pool) nitroDataTask(req work.TaskRequest) {
+	defer p.poolWG.Done()
 	timeNow := time.Now().UnixNano()
 	p.logger.Debug("Recieved nitroData Task", zap.String("TaskType", req.ReqType().String()), zap.Int64("TaskTS", timeNow))
 	R := req.(*nitroTaskReq)
+	if R.ctx.Err() != nil {
+		p.logger.Debug("skipping nitroData Task, context cancelled", zap.String("TaskType", req.ReqType().String()), zap.Int64("TaskTS", timeNow))
+		if R.ResultChan() != nil {
+			close(R.ResultChan())
+		}
+		return
+	}
 	switch data := R.data.(type) {
 	case ServiceStats:
 		p.logger.Debug("Identified nitroData Task Type as ServiceStats", zap.String("TaskType", req.ReqType().String()), zap.Int64("TaskTS", timeNow))
 		p.logger.Debug("Looking up Service VIP Name", zap.String("TaskType", req.ReqType().String()), zap.Int64("TaskTS", timeNow), zap.String("Lookup", data.Name))
 		data.ServiceName = p.vipMap.getMapping(p.lbserver.URL, data.Name, p.logger)
-		promReq := newPromTask(data)
-		success := p.submit(promReq)
-		p.logger.Debug("Sending nitroProm Task", zap.String("TaskType", req.ReqType().String()), zap.Int64("TaskTS", timeNow), zap.Bool("successful", success))
+		p.dispatchToSinks(data)
 		if R.ResultChan() != nil {
 			close(R.ResultChan())
 		}
@@ -227,6 +399,7 @@ func (p *Pool) nitroDataTask(req work.TaskRequest) {
 }
 
 func (p *Pool) nitroPromTask(req work.TaskRequest) {
+	defer p.poolWG.Done()
 	timeNow := time.Now().UnixNano()
 	p.logger.Debug("Recieved nitroProm Task", zap.String("TaskType", req.ReqType().String()), zap.Int64("TaskTS", timeNow))
 	R := req.(*nitroTaskReq)
@@ -244,43 +417,82 @@ func (p *Pool) nitroPromTask(req work.TaskRequest) {
 	p.logger.Debug("Completed nitroProm Task", zap.String("TaskType", req.ReqType().String()), zap.Int64("TaskTS", timeNow))
 }
 
+// dispatchToSinks submits data to every sink configured on the pool's
+// LBServer (Prometheus, OTLP, or both), defaulting to Prometheus alone when
+// none is configured. Each sink is just another terminal task submitted
+// through the existing worker pool, so it gets the same backoff and
+// shutdown behavior as the rest of the pipeline rather than a dedicated
+// goroutine per metric.
+func (p *Pool) dispatchToSinks(data NitroData) {
+	sinks := p.lbserver.Sinks
+	if len(sinks) == 0 {
+		sinks = []string{sinkPrometheus}
+	}
+	for _, sink := range sinks {
+		switch {
+		case sink == sinkOTLP && p.otlp != nil:
+			otlpReq := p.newOTLPReq(data)
+			success := p.submit(otlpReq)
+			p.logger.Debug("Sending nitroOTLP Task", zap.Bool("successful", success))
+		case sink == sinkOTLP:
+			// newOTLPExporter failed at pool construction time and the
+			// otlp sink was logged as disabled; nitroOTLP was never
+			// AddTask'd, so submitting here would leak poolWG forever.
+		default:
+			promReq := p.newPromTask(data)
+			success := p.submit(promReq)
+			p.logger.Debug("Sending nitroProm Task", zap.Bool("successful", success))
+		}
+	}
+}
+
 type nitroTaskReq struct {
 	taskID  TaskID
 	nitroID netscaler.StatsType
 	targets []string
 	data    interface{}
 	result  chan interface{}
+	// ctx is the Pool's context at the time the request was built. Task
+	// handlers that do meaningful post-collection work (writing to the vip
+	// map, pushing to a sink) check it before doing that work, so a task
+	// still queued when Shutdown cancels the Pool's context doesn't run
+	// against state that's already being torn down.
+	ctx context.Context
 }
 
-func newNitroAPIReq(id netscaler.StatsType, targets ...string) *nitroTaskReq {
+func (p *Pool) newNitroAPIReq(id netscaler.StatsType, targets ...string) *nitroTaskReq {
 	return &nitroTaskReq{
 		taskID:  nitroTaskAPI,
 		nitroID: id,
 		targets: targets,
 		result:  work.NewResultChannel(),
+		ctx:     p.ctx,
 	}
 }
 
-func newNitroRawReq(n NitroRaw) *nitroTaskReq {
+func (p *Pool) newNitroRawReq(n NitroRaw) *nitroTaskReq {
 	return &nitroTaskReq{
 		taskID: nitroTaskRaw,
 		data:   n,
 		result: work.NewResultChannel(),
+		ctx:    p.ctx,
 	}
 }
 
-func newNitroDataReq(n NitroData) *nitroTaskReq {
+func (p *Pool) newNitroDataReq(n NitroData) *nitroTaskReq {
 	return &nitroTaskReq{
 		taskID: nitroTaskData,
 		data:   n,
 		result: work.NewResultChannel(),
+		ctx:    p.ctx,
 	}
 }
 
-func newPromTask(n NitroData) *nitroTaskReq {
+func (p *Pool) newPromTask(n NitroData) *nitroTaskReq {
 	return &nitroTaskReq{
 		taskID: nitroProm,
 		data:   n,
 		result: work.NewResultChannel(),
+		ctx:    p.ctx,
 	}
 }
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// TestCollectMetricsFlipBitGuardsConcurrentRuns verifies that the flipBit
+// guard in collectMetrics prevents a second collection pass from running
+// while one is already in flight for the same Pool.
+func TestCollectMetricsFlipBitGuardsConcurrentRuns(t *testing.T) {
+	var calls int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	p := &Pool{
+		logger: zap.NewNop(),
+		metricHandlers: map[string]metricHandleFunc{
+			"slow": func(p *Pool, wg *sync.WaitGroup) {
+				if wg != nil {
+					defer wg.Done()
+				}
+				atomic.AddInt32(&calls, 1)
+				close(started)
+				<-release
+			},
+		},
+	}
+
+	go p.collectMetrics(nil)
+	<-started
+
+	// A second attempt while the first is still running must be skipped by
+	// the flipBit guard rather than racing with it.
+	p.collectMetrics(nil)
+	close(release)
+	time.Sleep(10 * time.Millisecond)
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("expected exactly 1 collection to run while the guard holds, got %d", got)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jbvmio/netscaler"
+)
+
+// RawServiceStats is the payload as returned by the Nitro API.
+type RawServiceStats []byte
+
+// Len returns the size of the underlying []byte.
+func (r RawServiceStats) Len() int {
+	return len(r)
+}
+
+// ServiceStats represents the data returned from the /stat/service Nitro API endpoint.
+type ServiceStats struct {
+	Name string `json:"name"`
+	// ServiceName is populated from the VIP mapping after collection, not
+	// from the Nitro payload itself.
+	ServiceName                  string   `json:"-"`
+	Throughput                   string   `json:"throughput"`
+	AvgTimeToFirstByte           string   `json:"avgsvrttfb"`
+	State                        CurState `json:"state"`
+	TotalRequests                string   `json:"totalrequests"`
+	TotalResponses               string   `json:"totalresponses"`
+	TotalRequestBytes            string   `json:"totalrequestbytes"`
+	TotalResponseBytes           string   `json:"totalresponsebytes"`
+	CurrentClientConnections     string   `json:"curclntconnections"`
+	SurgeCount                   string   `json:"surgecount"`
+	CurrentServerConnections     string   `json:"cursrvrconnections"`
+	ServerEstablishedConnections string   `json:"svrestablishedconn"`
+	CurrentReusePool             string   `json:"curreusepool"`
+	MaxClients                   string   `json:"maxclients"`
+	CurrentLoad                  string   `json:"curload"`
+	ServiceHits                  string   `json:"vsvrservicehits"`
+	ActiveTransactions           string   `json:"activetransactions"`
+}
+
+// NitroType implements the NitroData interface.
+func (s ServiceStats) NitroType() string {
+	return servicesSubsystem
+}
+
+// serviceProbe implements Probe for the service subsystem, migrating
+// processSvcStats off the old metricsMap dispatch and onto the Probe
+// registry so it self-registers via init() instead of being wired into
+// Pool by name.
+type serviceProbe struct {
+	pool *Pool
+}
+
+func newServiceProbe(p *Pool) Probe {
+	return &serviceProbe{pool: p}
+}
+
+// NitroStatsType implements Probe.
+func (pr *serviceProbe) NitroStatsType() netscaler.StatsType {
+	return netscaler.StatsTypeService
+}
+
+// Collect implements Probe.
+func (pr *serviceProbe) Collect(ctx context.Context) ([]NitroData, error) {
+	services, err := getServiceStats(pr.pool, pr.NitroStatsType())
+	if err != nil {
+		return nil, err
+	}
+	data := make([]NitroData, 0, len(services))
+	for _, s := range services {
+		data = append(data, s)
+	}
+	return data, nil
+}
+
+func init() {
+	RegisterSubsystem(servicesSubsystem, newServiceProbe)
+}
+
+// getServiceStats fetches and parses service stats through P.nitroAPICall,
+// the same client-pool selection, retry/backoff and circuit breaker
+// getLBVServerStatsViaPool uses for the lbvserver subsystem. statsType comes
+// from the caller's Probe.NitroStatsType() rather than being hardcoded here.
+func getServiceStats(P *Pool, statsType netscaler.StatsType) ([]ServiceStats, error) {
+	var services []ServiceStats
+	if !P.breaker.allow() {
+		return services, errCircuitOpen
+	}
+	b, err := P.nitroAPICall(func(c NitroClient) ([]byte, error) {
+		return c.GetAll(statsType)
+	})
+	if err != nil {
+		return services, err
+	}
+	tmp := struct {
+		Target *[]ServiceStats `json:"service"`
+	}{Target: &services}
+	if err := json.Unmarshal(b, &tmp); err != nil {
+		return services, err
+	}
+	return services, nil
+}